@@ -0,0 +1,138 @@
+package shares
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/tendermint/tendermint/pkg/consts"
+	coretypes "github.com/tendermint/tendermint/types"
+)
+
+// IndexedShare pairs a raw share with its absolute position in the square so
+// that a streaming consumer can process shares out of order without losing
+// track of where each one belongs.
+type IndexedShare struct {
+	Index int
+	Share []byte
+}
+
+// SharesBuilder assembles the shares of a square into a single, pre-sized
+// [][]byte instead of the chained appends that Split used to perform. Each
+// region (tx, evidence, padding, messages, tail) is written directly into its
+// final position, which avoids the repeated slice growth that the four
+// intermediate slices incurred.
+type SharesBuilder struct {
+	data       coretypes.Data
+	squareSize uint64
+	shares     [][]byte
+	cursor     int
+}
+
+// NewSharesBuilder validates data and prepares a builder whose output slice
+// is already sized to the full square (OriginalSquareSize^2).
+func NewSharesBuilder(data coretypes.Data) (*SharesBuilder, error) {
+	if data.OriginalSquareSize == 0 || !powerOf2(data.OriginalSquareSize) {
+		return nil, fmt.Errorf("square size is not a power of two: %d", data.OriginalSquareSize)
+	}
+	wantShareCount := int(data.OriginalSquareSize * data.OriginalSquareSize)
+	return &SharesBuilder{
+		data:       data,
+		squareSize: data.OriginalSquareSize,
+		shares:     make([][]byte, wantShareCount),
+	}, nil
+}
+
+// Build assembles the full, ordered share set for the square into the
+// pre-allocated slice. It is equivalent to Split, but fills a single
+// pre-sized slice instead of chaining four appends together.
+func (b *SharesBuilder) Build() ([][]byte, error) {
+	if err := b.assemble(context.Background(), nil); err != nil {
+		return nil, err
+	}
+	return b.shares, nil
+}
+
+// EmitShares builds the square and streams each share to out tagged with its
+// absolute position, region by region (tx, evidence, padding, messages,
+// tail). This lets a downstream consumer, such as the DA layer or block
+// propagation, start hashing or erasure-coding the tx region as soon as it is
+// written instead of waiting for SplitMessages, typically the most
+// expensive region, to finish. EmitShares does not close out; the caller
+// owns the channel's lifecycle.
+func (b *SharesBuilder) EmitShares(ctx context.Context, out chan<- IndexedShare) error {
+	return b.assemble(ctx, out)
+}
+
+func (b *SharesBuilder) assemble(ctx context.Context, out chan<- IndexedShare) error {
+	if err := b.writeRegion(ctx, out, SplitTxs(b.data.Txs)); err != nil {
+		return err
+	}
+
+	evdShares, err := SplitEvidence(b.data.Evidence.Evidence)
+	if err != nil {
+		return err
+	}
+	if err := b.writeRegion(ctx, out, evdShares); err != nil {
+		return err
+	}
+
+	// msgIndexes will be nil if we are working with a list of txs that do not
+	// have a msg index. this preserves backwards compatibility with old
+	// blocks that do not follow the non-interactive defaults
+	msgIndexes := ExtractShareIndexes(b.data.Txs)
+	sort.Slice(msgIndexes, func(i, j int) bool { return msgIndexes[i] < msgIndexes[j] })
+	if msgIndexes != nil && int(msgIndexes[0]) != b.cursor {
+		return ErrUnexpectedFirstMessageShareIndex
+	}
+
+	if len(b.data.Messages.MessagesList) > 0 {
+		msgShareStart, _ := NextAlignedPowerOfTwo(
+			b.cursor,
+			MsgSharesUsed(len(b.data.Messages.MessagesList[0].Data)),
+			int(b.squareSize),
+		)
+		ns := consts.TxNamespaceID
+		if len(evdShares) > 0 {
+			ns = consts.EvidenceNamespaceID
+		}
+		padding := namespacedPaddedShares(ns, msgShareStart-b.cursor).RawShares()
+		if err := b.writeRegion(ctx, out, padding); err != nil {
+			return err
+		}
+	}
+
+	msgShares, err := SplitMessages(b.cursor, msgIndexes, b.data.Messages.MessagesList)
+	if err != nil {
+		return err
+	}
+	if err := b.writeRegion(ctx, out, msgShares); err != nil {
+		return err
+	}
+
+	tailShares := TailPaddingShares(len(b.shares) - b.cursor).RawShares()
+	return b.writeRegion(ctx, out, tailShares)
+}
+
+// writeRegion copies shares into their final position in the pre-allocated
+// square and advances the cursor. If out is non-nil, each share is also
+// streamed tagged with its absolute index before the next region is built,
+// so a subscriber never has to wait for the whole square to be ready.
+func (b *SharesBuilder) writeRegion(ctx context.Context, out chan<- IndexedShare, shares [][]byte) error {
+	start := b.cursor
+	copy(b.shares[start:], shares)
+	b.cursor += len(shares)
+
+	if out == nil {
+		return nil
+	}
+
+	for i, s := range shares {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- IndexedShare{Index: start + i, Share: s}:
+		}
+	}
+	return nil
+}
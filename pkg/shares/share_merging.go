@@ -0,0 +1,313 @@
+package shares
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/tendermint/tendermint/pkg/consts"
+	coretypes "github.com/tendermint/tendermint/types"
+)
+
+// ErrInvalidPadding is returned by Join when a square's padding shares do not
+// satisfy the non-interactive default rules: the first message must start at
+// the current share count, and every subsequent message must begin at the
+// index NextAlignedPowerOfTwo computes for it.
+var ErrInvalidPadding = errors.New("shares: padding does not follow the non-interactive default rules")
+
+// Join reassembles a square of namespaced shares back into block data. It is
+// the inverse of Split: for well-formed input, Split(Join(shares, size))
+// reproduces shares, and Join(Split(data), data.OriginalSquareSize)
+// reproduces data. Join verifies that the message start indexes recovered
+// from the reconstructed transactions agree with where the message shares
+// actually begin, and rejects squares whose padding does not satisfy the
+// non-interactive default rules.
+func Join(shares [][]byte, squareSize uint64) (coretypes.Data, error) {
+	if squareSize == 0 || !powerOf2(squareSize) {
+		return coretypes.Data{}, fmt.Errorf("square size is not a power of two: %d", squareSize)
+	}
+	wantShareCount := int(squareSize * squareSize)
+	if len(shares) != wantShareCount {
+		return coretypes.Data{}, fmt.Errorf("unexpected number of shares: got %d want %d", len(shares), wantShareCount)
+	}
+
+	cursor := 0
+	txNID := []byte(consts.TxNamespaceID)
+	evdNID := []byte(consts.EvidenceNamespaceID)
+
+	// namespacedPaddedShares tags tx/evidence padding with the namespace of
+	// whichever region precedes it, so a contiguous run of txNID/evdNID
+	// shares is not, by itself, proof that every share in it is real data.
+	// parseDelimitedShares tells us exactly how many of the shares it was
+	// given actually held real items; we advance cursor by that count, not
+	// by the length of the namespace run.
+	txRun := namespaceRun(shares, cursor, txNID)
+	rawTxs, txConsumed, err := parseDelimitedShares(txRun)
+	if err != nil {
+		return coretypes.Data{}, fmt.Errorf("parsing tx shares: %w", err)
+	}
+	cursor += txConsumed
+
+	txs := make(coretypes.Txs, len(rawTxs))
+	for i, t := range rawTxs {
+		txs[i] = coretypes.Tx(t)
+	}
+
+	evdRun := namespaceRun(shares, cursor, evdNID)
+	rawEvd, evdConsumed, err := parseDelimitedShares(evdRun)
+	if err != nil {
+		return coretypes.Data{}, fmt.Errorf("parsing evidence shares: %w", err)
+	}
+	cursor += evdConsumed
+
+	evd := make(coretypes.EvidenceList, len(rawEvd))
+	for i, e := range rawEvd {
+		ev, err := coretypes.EvidenceFromBytes(e)
+		if err != nil {
+			return coretypes.Data{}, fmt.Errorf("decoding evidence %d: %w", i, err)
+		}
+		evd[i] = ev
+	}
+
+	// msgIndexes/msgSizes will be nil if we are working with a list of txs
+	// that do not have a msg index. this preserves backwards compatibility
+	// with old blocks that do not follow the non-interactive defaults.
+	msgIndexes, msgSizes, err := extractMessageMeta(txs)
+	if err != nil {
+		return coretypes.Data{}, fmt.Errorf("extracting message metadata: %w", err)
+	}
+	if msgIndexes != nil {
+		sortMessageMeta(msgIndexes, msgSizes)
+	}
+	if msgIndexes != nil && int(msgIndexes[0]) < cursor {
+		return coretypes.Data{}, ErrUnexpectedFirstMessageShareIndex
+	}
+
+	// padding shares between the tx/evidence region and the first message
+	// share carry the same namespace as whichever of the two regions is
+	// non-empty, matching namespacedPaddedShares in Split. Unlike above, we
+	// don't get to infer their count from parseDelimitedShares (there is no
+	// message region to parse through yet), so we validate the padding
+	// namespace share-by-share instead of assuming every like-namespaced
+	// share from here on is padding.
+	paddingNID := txNID
+	if evdConsumed > 0 {
+		paddingNID = evdNID
+	}
+
+	var msgs []coretypes.Message
+	if msgIndexes != nil {
+		// the first message must start exactly at the current share count,
+		// as Split requires, and NextAlignedPowerOfTwo must place it there
+		// too, since Split writes the first message's shares at that aligned
+		// position. Join rejects any block that declares something else even
+		// if the intervening shares happen to carry the right namespace.
+		padEnd, _ := NextAlignedPowerOfTwo(cursor, MsgSharesUsed(int(msgSizes[0])), int(squareSize))
+		if int(msgIndexes[0]) != cursor || padEnd != cursor {
+			return coretypes.Data{}, ErrInvalidPadding
+		}
+		for i := cursor; i < padEnd; i++ {
+			if !bytes.Equal(namespaceOf(shares[i]), paddingNID) {
+				return coretypes.Data{}, ErrInvalidPadding
+			}
+		}
+		cursor = padEnd
+
+		msgs, err = parseMessages(shares, cursor, msgIndexes, msgSizes, int(squareSize))
+		if err != nil {
+			return coretypes.Data{}, err
+		}
+	}
+
+	return coretypes.Data{
+		Txs:                txs,
+		Evidence:           coretypes.EvidenceData{Evidence: evd},
+		Messages:           coretypes.Messages{MessagesList: msgs},
+		OriginalSquareSize: squareSize,
+	}, nil
+}
+
+// namespaceOf returns the namespace ID prefix of a raw share.
+func namespaceOf(share []byte) []byte {
+	return share[:consts.NamespaceSize]
+}
+
+// namespaceRun returns the contiguous run of shares starting at start that
+// carry namespace ns. The run may include trailing padding shares that
+// share that namespace; callers that need to know how many of the returned
+// shares are real data must get that count from whoever parses them (e.g.
+// parseDelimitedShares), not from the length of the run itself.
+func namespaceRun(shares [][]byte, start int, ns []byte) [][]byte {
+	end := start
+	for end < len(shares) && bytes.Equal(namespaceOf(shares[end]), ns) {
+		end++
+	}
+	return shares[start:end]
+}
+
+// parseDelimitedShares strips the namespace prefix from a run of contiguous
+// shares and decodes the remaining bytes as a sequence of uvarint
+// length-prefixed items, mirroring the delimiting that
+// ContiguousShareSplitter.WriteTx/WriteEvidence applies when writing items
+// into a share run. A zero-length delimiter is the padding sentinel
+// namespacedPaddedShares writes to fill out the region: it, and everything
+// after it, is not a real item. parseDelimitedShares reports how many of
+// the input shares actually held real items, since a namespace run handed
+// to it may extend past the real data into that padding.
+func parseDelimitedShares(shares [][]byte) (items [][]byte, consumedShares int, err error) {
+	if len(shares) == 0 {
+		return nil, 0, nil
+	}
+
+	shareDataEnd := make([]int, len(shares))
+	var data []byte
+	for i, s := range shares {
+		data = append(data, s[consts.NamespaceSize:]...)
+		shareDataEnd[i] = len(data)
+	}
+
+	consumed := 0
+	for consumed < len(data) {
+		length, n := binary.Uvarint(data[consumed:])
+		if n <= 0 {
+			return nil, 0, errors.New("invalid length delimiter in share data")
+		}
+		if length == 0 {
+			break
+		}
+
+		itemStart := consumed + n
+		itemEnd := itemStart + int(length)
+		if itemEnd > len(data) {
+			return nil, 0, fmt.Errorf("truncated item: want %d bytes, have %d", length, len(data)-itemStart)
+		}
+		items = append(items, data[itemStart:itemEnd])
+		consumed = itemEnd
+	}
+
+	if consumed == 0 {
+		return items, 0, nil
+	}
+	for i, end := range shareDataEnd {
+		if end >= consumed {
+			return items, i + 1, nil
+		}
+	}
+	return items, len(shares), nil
+}
+
+// ErrZeroLengthMessage is returned by Join when a malleated tx declares a
+// zero-length message. Join parses shares that may come from an untrusted
+// light-client peer, so a malformed declaration like this is rejected
+// outright rather than risking an empty message-share slice downstream.
+var ErrZeroLengthMessage = errors.New("shares: malleated tx declares a zero-length message")
+
+// extractMessageMeta walks txs and, for each malleated tx, pairs the share
+// index it committed its message to with that message's declared byte
+// length. The length comes from the wrapped PFB, not from the message
+// shares themselves: message shares carry no inline length delimiter, only
+// namespaced data padded out to a whole number of shares. It returns nil,
+// nil, nil if txs are from an old block that predates the non-interactive
+// defaults, mirroring ExtractShareIndexes.
+func extractMessageMeta(txs coretypes.Txs) (indexes []uint32, sizes []uint32, err error) {
+	for _, rawTx := range txs {
+		malleatedTx, isMalleated := coretypes.UnwrapMalleatedTx(rawTx)
+		if !isMalleated {
+			continue
+		}
+		if malleatedTx.ShareIndex == 0 {
+			return nil, nil, nil
+		}
+		if malleatedTx.MessageSize == 0 {
+			return nil, nil, ErrZeroLengthMessage
+		}
+		indexes = append(indexes, malleatedTx.ShareIndex)
+		sizes = append(sizes, malleatedTx.MessageSize)
+	}
+	return indexes, sizes, nil
+}
+
+// sortMessageMeta sorts indexes ascending, permuting sizes the same way so
+// sizes[i] still describes the message starting at indexes[i].
+func sortMessageMeta(indexes []uint32, sizes []uint32) {
+	order := make([]int, len(indexes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return indexes[order[i]] < indexes[order[j]] })
+
+	sortedIndexes := make([]uint32, len(indexes))
+	sortedSizes := make([]uint32, len(sizes))
+	for newPos, oldPos := range order {
+		sortedIndexes[newPos] = indexes[oldPos]
+		sortedSizes[newPos] = sizes[oldPos]
+	}
+	copy(indexes, sortedIndexes)
+	copy(sizes, sortedSizes)
+}
+
+// parseMessages decodes the message region of a square starting at cursor.
+// indexes and sizes (as returned by extractMessageMeta) give the absolute
+// share position and declared byte length of each message; the share count
+// derives from sizes via MsgSharesUsed, and the trailing padding in a
+// message's last share is trimmed off based on that declared length rather
+// than an inline delimiter, since message shares don't carry one. Every
+// message after the first must start exactly where NextAlignedPowerOfTwo
+// places it — the non-interactive default rule Split enforces when writing
+// padding — not merely somewhere at or past the previous message's end;
+// otherwise a block could declare arbitrary over-padded indexes and still
+// pass as long as the intervening shares carried the expected namespace.
+func parseMessages(shares [][]byte, cursor int, indexes []uint32, sizes []uint32, squareSize int) ([]coretypes.Message, error) {
+	msgs := make([]coretypes.Message, 0, len(indexes))
+	for i, idx := range indexes {
+		if int(idx) != cursor {
+			return nil, ErrInvalidPadding
+		}
+		if sizes[i] == 0 {
+			// extractMessageMeta already rejects this, but parseMessages
+			// must not trust its caller blindly: sizes[i] == 0 would make
+			// msgShares empty and namespaceOf(msgShares[0]) below would
+			// panic on untrusted input.
+			return nil, ErrZeroLengthMessage
+		}
+
+		used := MsgSharesUsed(int(sizes[i]))
+		end := cursor + used
+		if end > len(shares) || used == 0 {
+			return nil, fmt.Errorf("message %d overruns the square", i)
+		}
+		msgShares := shares[cursor:end]
+
+		var payload []byte
+		for _, s := range msgShares {
+			payload = append(payload, s[consts.NamespaceSize:]...)
+		}
+		if uint32(len(payload)) < sizes[i] {
+			return nil, fmt.Errorf("message %d: share payload shorter than its declared size", i)
+		}
+
+		ns := namespaceOf(msgShares[0])
+		msgs = append(msgs, coretypes.Message{NamespaceID: ns, Data: payload[:sizes[i]]})
+		cursor = end
+
+		if i+1 < len(indexes) {
+			next, _ := NextAlignedPowerOfTwo(cursor, MsgSharesUsed(int(sizes[i+1])), squareSize)
+			if int(indexes[i+1]) != next {
+				return nil, ErrInvalidPadding
+			}
+			if next > len(shares) {
+				return nil, ErrInvalidPadding
+			}
+			for j := cursor; j < next; j++ {
+				if !bytes.Equal(namespaceOf(shares[j]), ns) {
+					return nil, ErrInvalidPadding
+				}
+			}
+			cursor = next
+		}
+	}
+
+	return msgs, nil
+}
@@ -2,8 +2,6 @@ package shares
 
 import (
 	"errors"
-	"fmt"
-	"sort"
 
 	"github.com/tendermint/tendermint/pkg/consts"
 	coretypes "github.com/tendermint/tendermint/types"
@@ -18,65 +16,16 @@ var (
 	)
 )
 
+// Split separates block data into shares of square size `OriginalSquareSize`.
+// The shares are ordered as tx shares, then evidence shares, then message
+// shares, padded to a power of two as required by the non-interactive
+// defaults.
 func Split(data coretypes.Data) ([][]byte, error) {
-	if data.OriginalSquareSize == 0 || !powerOf2(data.OriginalSquareSize) {
-		return nil, fmt.Errorf("square size is not a power of two: %d", data.OriginalSquareSize)
-	}
-	wantShareCount := int(data.OriginalSquareSize * data.OriginalSquareSize)
-	currentShareCount := 0
-
-	txShares := SplitTxs(data.Txs)
-	currentShareCount += len(txShares)
-
-	evdShares, err := SplitEvidence(data.Evidence.Evidence)
+	builder, err := NewSharesBuilder(data)
 	if err != nil {
 		return nil, err
 	}
-	currentShareCount += len(evdShares)
-
-	// msgIndexes will be nil if we are working with a list of txs that do not
-	// have a msg index. this preserves backwards compatibility with old blocks
-	// that do not follow the non-interactive defaults
-	msgIndexes := ExtractShareIndexes(data.Txs)
-	sort.Slice(msgIndexes, func(i, j int) bool { return msgIndexes[i] < msgIndexes[j] })
-
-	var msgShares [][]byte
-	if msgIndexes != nil && int(msgIndexes[0]) != currentShareCount {
-		return nil, ErrUnexpectedFirstMessageShareIndex
-	}
-
-	var padding [][]byte
-	if len(data.Messages.MessagesList) > 0 {
-		msgShareStart, _ := NextAlignedPowerOfTwo(
-			currentShareCount,
-			MsgSharesUsed(len(data.Messages.MessagesList[0].Data)),
-			int(data.OriginalSquareSize),
-		)
-		ns := consts.TxNamespaceID
-		if len(evdShares) > 0 {
-			ns = consts.EvidenceNamespaceID
-		}
-		padding = namespacedPaddedShares(ns, msgShareStart-currentShareCount).RawShares()
-	}
-	currentShareCount += len(padding)
-
-	msgShares, err = SplitMessages(currentShareCount, msgIndexes, data.Messages.MessagesList)
-	if err != nil {
-		return nil, err
-	}
-	currentShareCount += len(msgShares)
-
-	tailShares := TailPaddingShares(wantShareCount - currentShareCount).RawShares()
-
-	// todo: optimize using a predefined slice
-	shares := append(append(append(append(
-		txShares,
-		evdShares...),
-		padding...),
-		msgShares...),
-		tailShares...)
-
-	return shares, nil
+	return builder.Build()
 }
 
 // ExtractShareIndexes iterates over the transactions and extracts the share
@@ -123,17 +72,29 @@ func SplitEvidence(evd coretypes.EvidenceList) ([][]byte, error) {
 	return writer.Export().RawShares(), nil
 }
 
+// SplitMessages splits msgs into shares starting at the given cursor. If
+// indexes is non-nil, the message regions are already known to be
+// non-overlapping (one per malleated tx), so the work is fanned out across a
+// worker pool via splitMessagesParallel. indexes is nil for old blocks that
+// predate the non-interactive defaults, in which case messages are split
+// serially since there is no index information to partition on.
 func SplitMessages(cursor int, indexes []uint32, msgs []coretypes.Message) ([][]byte, error) {
 	if indexes != nil && len(indexes) != len(msgs) {
 		return nil, ErrIncorrectNumberOfIndexes
 	}
+	if indexes == nil {
+		return splitMessagesSerial(cursor, msgs)
+	}
+	return splitMessagesParallel(cursor, indexes, msgs)
+}
+
+// splitMessagesSerial is the pre-parallel code path, used for old blocks
+// whose txs carry no share indexes and therefore give us nothing to
+// partition the message region on.
+func splitMessagesSerial(cursor int, msgs []coretypes.Message) ([][]byte, error) {
 	writer := NewMessageShareSplitter()
-	for i, msg := range msgs {
+	for _, msg := range msgs {
 		writer.Write(msg)
-		if indexes != nil && len(indexes) > i+1 {
-			paddedShareCount := int(indexes[i+1]) - (writer.Count() + cursor)
-			writer.WriteNamespacedPaddedShares(paddedShareCount)
-		}
 	}
 	return writer.Export().RawShares(), nil
-}
\ No newline at end of file
+}
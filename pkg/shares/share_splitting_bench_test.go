@@ -0,0 +1,74 @@
+package shares
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	coretypes "github.com/tendermint/tendermint/types"
+)
+
+// genMsgsForBenchmark builds a set of messages whose sizes follow a mixed
+// distribution (small, medium, and about a quarter of a row), together with the
+// non-interactive default indexes they would be assigned at the given
+// square size, starting right after a single-share tx region.
+func genMsgsForBenchmark(squareSize int, count int) (msgs []coretypes.Message, indexes []uint32) {
+	rnd := rand.New(rand.NewSource(1))
+	sizes := []int{
+		200,                    // small: fits in a single share
+		4_000,                  // medium: a handful of shares
+		(squareSize / 4) * 256, // large: a meaningful fraction of a row
+	}
+
+	cursor := 1 // one share reserved for the tx region
+	for i := 0; i < count; i++ {
+		size := sizes[rnd.Intn(len(sizes))]
+		msg := coretypes.Message{
+			NamespaceID: []byte{0, 0, 0, 0, 0, 0, 0, byte(i + 1)},
+			Data:        make([]byte, size),
+		}
+		start, _ := NextAlignedPowerOfTwo(cursor, MsgSharesUsed(size), squareSize)
+		indexes = append(indexes, uint32(start))
+		msgs = append(msgs, msg)
+		cursor = start + MsgSharesUsed(size)
+	}
+
+	return msgs, indexes
+}
+
+func BenchmarkSplitMessages(b *testing.B) {
+	squareSizes := []int{32, 64, 128}
+	for _, squareSize := range squareSizes {
+		msgCount := squareSize // scale message count with the square so larger squares stay meaningfully full
+		msgs, indexes := genMsgsForBenchmark(squareSize, msgCount)
+
+		// singleWorker runs the exact same per-group algorithm
+		// splitMessagesParallel fans out across a worker pool — same
+		// indexes, same inter-message padding — just on a single goroutine,
+		// via splitMsgGroup covering the whole message range. That makes it
+		// an apples-to-apples baseline: splitMessagesSerial is not
+		// comparable here since the indexes == nil path it takes writes no
+		// inter-message padding and does less work.
+		b.Run(benchName("singleWorker", squareSize), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := splitMsgGroup(1, indexes, msgs, msgGroup{start: 0, end: len(msgs)}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(benchName("parallel", squareSize), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := splitMessagesParallel(1, indexes, msgs); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func benchName(mode string, squareSize int) string {
+	return fmt.Sprintf("%s/square=%d", mode, squareSize)
+}
@@ -0,0 +1,103 @@
+package shares
+
+import (
+	"runtime"
+	"sync"
+
+	coretypes "github.com/tendermint/tendermint/types"
+)
+
+// msgGroup is a contiguous, non-overlapping range of msgs (and their
+// matching indexes) that can be split independently of every other group.
+type msgGroup struct {
+	start, end int // [start, end) into msgs/indexes
+}
+
+// splitMessagesParallel splits msgs into shares using a worker pool sized by
+// GOMAXPROCS. Because indexes gives the absolute share position of every
+// message up front, msgs can be partitioned into contiguous groups and each
+// group split on its own goroutine with its own MessageShareSplitter; the
+// results are then written directly into their final position in the
+// output, with no further stitching required.
+func splitMessagesParallel(cursor int, indexes []uint32, msgs []coretypes.Message) ([][]byte, error) {
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+
+	last := len(msgs) - 1
+	total := int(indexes[last]) + MsgSharesUsed(len(msgs[last].Data)) - cursor
+	out := make([][]byte, total)
+
+	groups := partitionMsgGroups(len(msgs), runtime.GOMAXPROCS(0))
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		groupErr error
+	)
+
+	for _, g := range groups {
+		g := g
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			shares, err := splitMsgGroup(cursor, indexes, msgs, g)
+			if err != nil {
+				errOnce.Do(func() { groupErr = err })
+				return
+			}
+			rel := int(indexes[g.start]) - cursor
+			copy(out[rel:], shares)
+		}()
+	}
+	wg.Wait()
+
+	if groupErr != nil {
+		return nil, groupErr
+	}
+	return out, nil
+}
+
+// splitMsgGroup splits the [g.start, g.end) slice of msgs on its own
+// MessageShareSplitter, writing padding after each message up to
+// indexes[i+1] (including after the group's last message, up to where the
+// next group begins), since that padding belongs to whichever message
+// precedes it.
+func splitMsgGroup(cursor int, indexes []uint32, msgs []coretypes.Message, g msgGroup) ([][]byte, error) {
+	writer := NewMessageShareSplitter()
+	for i := g.start; i < g.end; i++ {
+		writer.Write(msgs[i])
+		if i+1 < len(indexes) {
+			paddedShareCount := int(indexes[i+1]) - (writer.Count() + int(indexes[g.start]))
+			writer.WriteNamespacedPaddedShares(paddedShareCount)
+		}
+	}
+	return writer.Export().RawShares(), nil
+}
+
+// partitionMsgGroups divides [0, n) into at most numWorkers contiguous,
+// roughly equal-sized groups.
+func partitionMsgGroups(n, numWorkers int) []msgGroup {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	if numWorkers > n {
+		numWorkers = n
+	}
+
+	groups := make([]msgGroup, 0, numWorkers)
+	base, rem := n/numWorkers, n%numWorkers
+	start := 0
+	for i := 0; i < numWorkers; i++ {
+		size := base
+		if i < rem {
+			size++
+		}
+		if size == 0 {
+			continue
+		}
+		groups = append(groups, msgGroup{start: start, end: start + size})
+		start += size
+	}
+	return groups
+}
@@ -0,0 +1,152 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/testground/sdk-go/run"
+	"github.com/testground/sdk-go/runtime"
+	"github.com/testground/sdk-go/sync"
+	"gopkg.in/yaml.v3"
+)
+
+// NamespaceStrategy controls how a load stage picks the namespace each PFB
+// is submitted under.
+type NamespaceStrategy string
+
+const (
+	NamespaceStrategyFixed      NamespaceStrategy = "fixed"
+	NamespaceStrategyRoundRobin NamespaceStrategy = "round-robin"
+	NamespaceStrategyRandom     NamespaceStrategy = "random"
+)
+
+// NodeGroup names which class of node a load stage targets.
+type NodeGroup string
+
+const (
+	NodeGroupLeader    NodeGroup = "leader"
+	NodeGroupFollower  NodeGroup = "follower"
+	NodeGroupValidator NodeGroup = "validator"
+)
+
+// LoadStage describes one phase of a load test: how much traffic to send,
+// shaped how, for how long, and against which nodes.
+type LoadStage struct {
+	Duration          time.Duration     `json:"duration" yaml:"duration"`
+	TPS               int               `json:"tps" yaml:"tps"`
+	BlobSizeBytes     int               `json:"blob_size_bytes" yaml:"blob_size_bytes"`
+	BlobCountPerTx    int               `json:"blob_count_per_tx" yaml:"blob_count_per_tx"`
+	NamespaceStrategy NamespaceStrategy `json:"namespace_strategy" yaml:"namespace_strategy"`
+	TargetGroup       NodeGroup         `json:"target_group" yaml:"target_group"`
+}
+
+// LoadProfile is an ordered list of stages, committed to the repo as a YAML
+// file (load-config.yml) and shared with every instance via LoadProfileTopic
+// so a PFB/blob load benchmark can be reproduced without recompiling the
+// test plan.
+type LoadProfile struct {
+	Stages []LoadStage `json:"stages" yaml:"stages"`
+}
+
+// LoadProfileFromYAML parses a LoadProfile out of a load-config.yml file's
+// contents.
+func LoadProfileFromYAML(data []byte) (LoadProfile, error) {
+	var profile LoadProfile
+	if err := yaml.Unmarshal(data, &profile); err != nil {
+		return LoadProfile{}, fmt.Errorf("parsing load profile: %w", err)
+	}
+	return profile, nil
+}
+
+// LoadProfileTopic is the topic the leader publishes the run's LoadProfile
+// on, alongside ConfigTopic, so every instance executes the same schedule.
+var LoadProfileTopic = sync.NewTopic("load-profile", LoadProfile{})
+
+func PublishLoadProfile(ctx context.Context, initCtx *run.InitContext, profile LoadProfile) error {
+	_, err := initCtx.SyncClient.Publish(ctx, LoadProfileTopic, profile)
+	return err
+}
+
+func DownloadLoadProfile(ctx context.Context, initCtx *run.InitContext) (LoadProfile, error) {
+	profiles, err := DownloadSync(ctx, initCtx, LoadProfileTopic, LoadProfile{}, 1)
+	if err != nil {
+		return LoadProfile{}, err
+	}
+	if len(profiles) != 1 {
+		return LoadProfile{}, fmt.Errorf("no load profile was downloaded despite there not being an error")
+	}
+	return profiles[0], nil
+}
+
+// StageComplete is published by every instance once it finishes executing a
+// given load profile stage. Downloading TestInstanceCount of them is what
+// gates the transition into the next stage.
+type StageComplete struct {
+	Stage int    `json:"stage"`
+	Group string `json:"group"`
+}
+
+// stageState names the sync.State barrier gating the transition out of
+// stage n.
+func stageState(n int) sync.State {
+	return sync.State(fmt.Sprintf("stage-%d-complete", n))
+}
+
+func stageTopic(n int) *sync.Topic {
+	return sync.NewTopic(string(stageState(n)), StageComplete{})
+}
+
+// awaitStage publishes this instance's completion of stage n and blocks
+// until every instance in the run has done the same, using the same
+// DownloadSync primitive SyncStatus already uses for Status.
+func awaitStage(ctx context.Context, runenv *runtime.RunEnv, initCtx *run.InitContext, n int) error {
+	topic := stageTopic(n)
+	_, err := initCtx.SyncClient.Publish(ctx, topic, StageComplete{Stage: n, Group: runenv.TestGroupID})
+	if err != nil {
+		return err
+	}
+	_, err = DownloadSync(ctx, initCtx, topic, StageComplete{}, runenv.TestInstanceCount)
+	return err
+}
+
+// StageRunner generates load for a single stage against this instance's
+// node(s) and returns once the stage is done, or ctx is cancelled. Test
+// plans supply their own StageRunner since building and broadcasting PFBs is
+// specific to how the plan wires up its nodes.
+type StageRunner func(ctx context.Context, runenv *runtime.RunEnv, stage LoadStage) error
+
+// RunScenario drives this instance through every stage of profile in order,
+// invoking run for each stage this instance's group participates in, then
+// waiting at a stage-N-complete barrier before moving on. This keeps all
+// instances ramping up, holding, and tearing down in lockstep, regardless of
+// how long any individual instance's load generation takes for a stage.
+func RunScenario(ctx context.Context, runenv *runtime.RunEnv, initCtx *run.InitContext, profile LoadProfile, run StageRunner) error {
+	for i, stage := range profile.Stages {
+		if stageTargets(stage, runenv.TestGroupID) {
+			stageCtx, cancel := context.WithTimeout(ctx, stage.Duration)
+			err := run(stageCtx, runenv, stage)
+			cancel()
+			// A StageRunner that generates load for the whole stage duration
+			// is expected to return ctx.Err() once stageCtx's deadline fires;
+			// that's normal completion, not a failure. Only ctx itself being
+			// cancelled (the parent, not the per-stage timeout) should abort
+			// the scenario.
+			if err != nil && !(errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil) {
+				return fmt.Errorf("running stage %d: %w", i, err)
+			}
+		}
+
+		if err := awaitStage(ctx, runenv, initCtx, i); err != nil {
+			return fmt.Errorf("waiting on stage %d barrier: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// stageTargets reports whether an instance in group should run stage. An
+// empty TargetGroup means the stage applies to every group.
+func stageTargets(stage LoadStage, group string) bool {
+	return stage.TargetGroup == "" || string(stage.TargetGroup) == group
+}
@@ -0,0 +1,132 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/testground/sdk-go/run"
+	"github.com/testground/sdk-go/runtime"
+	"github.com/testground/sdk-go/sync"
+)
+
+// MetricSample is one instance's measurements at a point in a load profile
+// run, published over MetricsTopic so the leader can aggregate
+// apples-to-apples throughput/latency numbers across runs.
+type MetricSample struct {
+	Timestamp               int64  `json:"timestamp"`
+	GlobalSequence          int64  `json:"global_sequence"`
+	Group                   string `json:"group"`
+	BlockHeight             int64  `json:"block_height"`
+	TxsIncluded             int    `json:"txs_included"`
+	BlobsIncluded           int    `json:"blobs_included"`
+	MempoolSize             int    `json:"mempool_size"`
+	ConsensusRoundLatencyMs int64  `json:"consensus_round_latency_ms"`
+	ShareSplitDurationNs    int64  `json:"share_split_duration_ns"`
+}
+
+// MetricsTopic is the topic instances publish MetricSamples on.
+var MetricsTopic = sync.NewTopic("metrics", MetricSample{})
+
+func PublishMetrics(ctx context.Context, initCtx *run.InitContext, sample MetricSample) error {
+	_, err := initCtx.SyncClient.Publish(ctx, MetricsTopic, sample)
+	return err
+}
+
+// CollectMetrics downloads the expected number of MetricSamples published
+// for a run, built on the same generic DownloadSync used for Config and
+// Status.
+func CollectMetrics(ctx context.Context, initCtx *run.InitContext, expected int) ([]MetricSample, error) {
+	return DownloadSync(ctx, initCtx, MetricsTopic, MetricSample{}, expected)
+}
+
+// MetricSummary holds the min/median/p95/max of a single numeric field
+// across every sample in a group.
+type MetricSummary struct {
+	Min    float64 `json:"min"`
+	Median float64 `json:"median"`
+	P95    float64 `json:"p95"`
+	Max    float64 `json:"max"`
+}
+
+// RunSummary is the leader's aggregation of a run's MetricSamples, keyed
+// first by group then by field name.
+type RunSummary map[string]map[string]MetricSummary
+
+// metricFields lists the MetricSample fields that get summarized, paired
+// with an accessor so SummarizeMetrics doesn't need one hand-written branch
+// per field.
+var metricFields = map[string]func(MetricSample) float64{
+	"block_height":               func(s MetricSample) float64 { return float64(s.BlockHeight) },
+	"txs_included":               func(s MetricSample) float64 { return float64(s.TxsIncluded) },
+	"blobs_included":             func(s MetricSample) float64 { return float64(s.BlobsIncluded) },
+	"mempool_size":               func(s MetricSample) float64 { return float64(s.MempoolSize) },
+	"consensus_round_latency_ms": func(s MetricSample) float64 { return float64(s.ConsensusRoundLatencyMs) },
+	"share_split_duration_ns":    func(s MetricSample) float64 { return float64(s.ShareSplitDurationNs) },
+}
+
+// SummarizeMetrics aggregates samples into a per-group, per-field summary so
+// results are comparable apples-to-apples across runs of the load-profile
+// scenarios, without every test plan reinventing metric shipping.
+func SummarizeMetrics(samples []MetricSample) RunSummary {
+	byGroup := make(map[string][]MetricSample)
+	for _, s := range samples {
+		byGroup[s.Group] = append(byGroup[s.Group], s)
+	}
+
+	summary := make(RunSummary, len(byGroup))
+	for group, gs := range byGroup {
+		fields := make(map[string]MetricSummary, len(metricFields))
+		for name, accessor := range metricFields {
+			fields[name] = summarizeField(gs, accessor)
+		}
+		summary[group] = fields
+	}
+	return summary
+}
+
+func summarizeField(samples []MetricSample, field func(MetricSample) float64) MetricSummary {
+	values := make([]float64, len(samples))
+	for i, s := range samples {
+		values[i] = field(s)
+	}
+	sort.Float64s(values)
+
+	return MetricSummary{
+		Min:    values[0],
+		Median: percentile(values, 0.5),
+		P95:    percentile(values, 0.95),
+		Max:    values[len(values)-1],
+	}
+}
+
+// percentile linearly interpolates the p-th percentile (0 <= p <= 1) out of
+// an already-sorted slice.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	idx := p * float64(len(sorted)-1)
+	lo := int(idx)
+	hi := lo + 1
+	if hi >= len(sorted) {
+		return sorted[lo]
+	}
+	frac := idx - float64(lo)
+	return sorted[lo] + frac*(sorted[hi]-sorted[lo])
+}
+
+// RecordSummary emits every group/field summary in s via
+// runenv.R().RecordPoint, giving every load-profile run the same set of
+// queryable result points.
+func RecordSummary(runenv *runtime.RunEnv, s RunSummary) {
+	for group, fields := range s {
+		for field, summary := range fields {
+			prefix := fmt.Sprintf("%s.%s", group, field)
+			runenv.R().RecordPoint(prefix+".min", summary.Min)
+			runenv.R().RecordPoint(prefix+".median", summary.Median)
+			runenv.R().RecordPoint(prefix+".p95", summary.P95)
+			runenv.R().RecordPoint(prefix+".max", summary.Max)
+		}
+	}
+}